@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeviceInfo is the firmware/hardware identity reported by CmdGetVersion.
+// It rarely changes for a given physical printer, so CatPrinter caches it
+// after the first successful query.
+type DeviceInfo struct {
+	FirmwareVersion  string
+	HardwareRevision string
+	SerialNumber     string
+}
+
+// GetVersion sends the B1 Get Version command and returns the parsed
+// DeviceInfo, caching it on the printer so repeated calls (and the /info
+// endpoint) don't have to round-trip the BLE link every time.
+func (p *CatPrinter) GetVersion() (DeviceInfo, error) {
+	p.bleMu.Lock()
+	defer p.bleMu.Unlock()
+
+	if p.deviceInfo != nil {
+		return *p.deviceInfo, nil
+	}
+
+	if !p.IsConnected() {
+		return DeviceInfo{}, fmt.Errorf("printer not connected")
+	}
+
+	payload := []byte{0x00}
+	crc := calculateCRC8(payload)
+	packet := []byte{
+		Preamble1,     // 0x22
+		Preamble2,     // 0x21
+		CmdGetVersion, // 0xB1
+		0x00,          // Fixed byte
+		0x01, 0x00,    // Length (1 byte payload, little endian)
+		0x00,   // Payload
+		crc,    // CRC8 checksum
+		Footer, // 0xFF
+	}
+
+	_, err := p.controlChar.WriteWithoutResponse(packet)
+	if err != nil {
+		return DeviceInfo{}, fmt.Errorf("failed to send get-version command: %v", err)
+	}
+
+	select {
+	case buf := <-p.notifications:
+		info, err := parseVersionResponse(buf)
+		if err != nil {
+			return DeviceInfo{}, err
+		}
+		p.deviceInfo = &info
+		return info, nil
+	case <-time.After(5 * time.Second):
+		return DeviceInfo{}, fmt.Errorf("get-version response timeout")
+	}
+}
+
+// parseVersionResponse decodes a B1 response. The payload layout isn't
+// formally documented; we follow the same major.minor-then-ASCII-serial
+// shape observed on this protocol family's other commands.
+func parseVersionResponse(buf []byte) (DeviceInfo, error) {
+	if len(buf) < 9 {
+		return DeviceInfo{}, fmt.Errorf("invalid version response length: %d", len(buf))
+	}
+	if buf[0] != Preamble1 || buf[1] != Preamble2 {
+		return DeviceInfo{}, fmt.Errorf("invalid response preamble: got %02x%02x", buf[0], buf[1])
+	}
+	if buf[2] != CmdGetVersion {
+		return DeviceInfo{}, fmt.Errorf("unexpected response command ID: 0x%02X", buf[2])
+	}
+
+	payloadLength := int(buf[4]) | (int(buf[5]) << 8)
+	if len(buf) < 6+payloadLength {
+		return DeviceInfo{}, fmt.Errorf("response too short for declared payload length: got %d, need %d",
+			len(buf), 6+payloadLength)
+	}
+	payload := buf[6 : 6+payloadLength]
+
+	var info DeviceInfo
+	if len(payload) >= 2 {
+		info.FirmwareVersion = fmt.Sprintf("%d.%d", payload[0], payload[1])
+	}
+	if len(payload) >= 3 {
+		info.HardwareRevision = fmt.Sprintf("rev%d", payload[2])
+	}
+	if len(payload) > 3 {
+		info.SerialNumber = strings.TrimRight(string(payload[3:]), "\x00")
+	}
+	return info, nil
+}
+
+// GetBattery sends the AB Get Battery command and returns the battery
+// percentage, also updating lastStatus.Battery so /status reflects it
+// without a separate round trip.
+func (p *CatPrinter) GetBattery() (int, error) {
+	p.bleMu.Lock()
+	defer p.bleMu.Unlock()
+
+	if !p.IsConnected() {
+		return 0, fmt.Errorf("printer not connected")
+	}
+
+	payload := []byte{0x00}
+	crc := calculateCRC8(payload)
+	packet := []byte{
+		Preamble1,     // 0x22
+		Preamble2,     // 0x21
+		CmdGetBattery, // 0xAB
+		0x00,          // Fixed byte
+		0x01, 0x00,    // Length (1 byte payload, little endian)
+		0x00,   // Payload
+		crc,    // CRC8 checksum
+		Footer, // 0xFF
+	}
+
+	_, err := p.controlChar.WriteWithoutResponse(packet)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send get-battery command: %v", err)
+	}
+
+	select {
+	case buf := <-p.notifications:
+		if len(buf) < 7 || buf[2] != CmdGetBattery {
+			return 0, fmt.Errorf("unexpected get-battery response: %x", buf)
+		}
+		level := int(buf[6])
+		p.lastStatus.Battery = level
+		return level, nil
+	case <-time.After(5 * time.Second):
+		return 0, fmt.Errorf("get-battery response timeout")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tinygo.org/x/bluetooth"
+)
+
+// DefaultAllowList is the set of local names Discover and Connect treat as
+// "a cat printer" when the caller doesn't pin a specific one. MXW01 is the
+// model this driver was built against; GB01/GB02 are the same protocol
+// family under different branding.
+var DefaultAllowList = []string{"MXW01", "GB01", "GB02"}
+
+const (
+	reconnectBackoffInitial = 1 * time.Second
+	reconnectBackoffMax     = 30 * time.Second
+)
+
+// DiscoveredPrinter is one result from Discover: a nearby device whose
+// local name matched the allow-list.
+type DiscoveredPrinter struct {
+	Name    string
+	Address bluetooth.Address
+	RSSI    int16
+}
+
+// PrinterTarget pins Connect/reconnect to a specific device, by name or by
+// address. The zero value means "any device on the allow-list".
+type PrinterTarget struct {
+	Name    string
+	Address bluetooth.Address
+
+	hasAddress bool
+}
+
+// ByName pins a target to a specific advertised local name.
+func ByName(name string) PrinterTarget {
+	return PrinterTarget{Name: name}
+}
+
+// ByAddress pins a target to a specific BLE address, for hosts with
+// multiple same-model printers where names alone can't disambiguate.
+func ByAddress(addr bluetooth.Address) PrinterTarget {
+	return PrinterTarget{Address: addr, hasAddress: true}
+}
+
+func (t PrinterTarget) String() string {
+	switch {
+	case t.hasAddress:
+		return t.Address.String()
+	case t.Name != "":
+		return t.Name
+	default:
+		return "any"
+	}
+}
+
+// matches reports whether a scan result satisfies this target: an exact
+// address match if one was pinned, an exact name match if one was pinned,
+// or - for the zero-value target - membership in allowList.
+func (t PrinterTarget) matches(name string, addr bluetooth.Address, allowList []string) bool {
+	if t.hasAddress {
+		return addr.String() == t.Address.String()
+	}
+	if t.Name != "" {
+		return name == t.Name
+	}
+	for _, allowed := range allowList {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Target returns the printer's currently pinned/connected target, for
+// diagnostics endpoints.
+func (p *CatPrinter) Target() PrinterTarget {
+	p.connMu.RLock()
+	defer p.connMu.RUnlock()
+	return p.target
+}
+
+// Discover scans for timeout and returns every device whose local name is
+// in allowList, most-recently-seen RSSI included. A nil allowList falls
+// back to DefaultAllowList.
+func (p *CatPrinter) Discover(ctx context.Context, timeout time.Duration) ([]DiscoveredPrinter, error) {
+	allowList := DefaultAllowList
+
+	found := make(map[string]DiscoveredPrinter)
+	err := p.adapter.Scan(func(adapter *bluetooth.Adapter, result bluetooth.ScanResult) {
+		name := result.LocalName()
+		for _, allowed := range allowList {
+			if name == allowed {
+				found[result.Address.String()] = DiscoveredPrinter{
+					Name:    name,
+					Address: result.Address,
+					RSSI:    result.RSSI,
+				}
+				break
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start scan: %v", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(timeout):
+	}
+	p.adapter.StopScan()
+
+	results := make([]DiscoveredPrinter, 0, len(found))
+	for _, d := range found {
+		results = append(results, d)
+	}
+	return results, nil
+}
+
+// supervise watches for the BLE link dropping after a successful Connect
+// and attempts to reconnect to the same target with capped exponential
+// backoff, so a mid-session disconnect (which these printers' firmware
+// does routinely) doesn't require restarting the server.
+func (p *CatPrinter) supervise() {
+	p.adapter.SetConnectHandler(func(device bluetooth.Device, connected bool) {
+		if connected || device.Address.String() != p.getDevice().Address.String() {
+			return
+		}
+
+		p.connMu.RLock()
+		intentional := p.disconnecting
+		p.connMu.RUnlock()
+		if intentional {
+			return
+		}
+
+		fmt.Println("Printer disconnected unexpectedly, will attempt to reconnect...")
+		p.setConnected(false)
+		p.drainNotifications()
+
+		go p.reconnectLoop()
+	})
+}
+
+// drainNotifications empties the notifications channel so a stale
+// in-flight response doesn't get matched against the next command sent
+// after reconnecting.
+func (p *CatPrinter) drainNotifications() {
+	for {
+		select {
+		case <-p.notifications:
+		default:
+			return
+		}
+	}
+}
+
+func (p *CatPrinter) reconnectLoop() {
+	p.connMu.RLock()
+	target := p.target
+	p.connMu.RUnlock()
+
+	delay := reconnectBackoffInitial
+	for !p.IsConnected() {
+		fmt.Printf("Reconnecting to %s...\n", target.String())
+		if err := p.Connect(target); err == nil {
+			fmt.Println("Reconnected successfully")
+			return
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+		if delay > reconnectBackoffMax {
+			delay = reconnectBackoffMax
+		}
+	}
+}
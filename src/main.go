@@ -1,17 +1,46 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/Codaea/cattaskprinter/src/render"
 	"github.com/gin-gonic/gin"
+	"tinygo.org/x/bluetooth"
 )
 
+// JobResponse is the JSON shape returned for a queued/printing job.
+type JobResponse struct {
+	ID         string `json:"id"`
+	State      string `json:"state"`
+	BytesSent  int    `json:"bytes_sent"`
+	TotalBytes int    `json:"total_bytes"`
+	Error      string `json:"error,omitempty"`
+}
+
+func jobToResponse(job *PrintJob) JobResponse {
+	bytesSent, totalBytes := job.Progress()
+	resp := JobResponse{
+		ID:         job.ID,
+		State:      string(job.State()),
+		BytesSent:  bytesSent,
+		TotalBytes: totalBytes,
+	}
+	if err := job.Err(); err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
 type PrintRequest struct {
-	Text     string `json:"text"`
-	QRCode   string `json:"qr_code,omitempty"`
-	FontSize int    `json:"font_size,omitempty"`
+	Text        string `json:"text"`
+	QRCode      string `json:"qr_code,omitempty"`
+	FontSize    int    `json:"font_size,omitempty"`
+	ImageBase64 string `json:"image_base64,omitempty"`
 }
 
 type PrintResponse struct {
@@ -20,13 +49,16 @@ type PrintResponse struct {
 }
 
 type StatusResponse struct {
-	Connected bool   `json:"connected"`
-	Battery   int    `json:"battery"`
-	Status    string `json:"status"`
-	Error     string `json:"error,omitempty"`
+	Connected bool     `json:"connected"`
+	Battery   int      `json:"battery"`
+	State     string   `json:"state"`
+	Errors    []string `json:"errors,omitempty"`
+	MTU       int      `json:"mtu"`
+	Message   string   `json:"message"`
 }
 
 var p *CatPrinter
+var jobQueue *JobQueue
 
 func main() {
 	// Initialize the cat printer
@@ -35,6 +67,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize printer: %v", err)
 	}
+	jobQueue = NewJobQueue(p)
 
 	// Setup HTTP server
 	r := gin.Default()
@@ -54,7 +87,14 @@ func main() {
 	// API endpoints
 	r.GET("/status", getStatus)
 	r.GET("/test-print", testPrint)
-	r.POST("/print")
+	r.POST("/print", print)
+	r.POST("/jobs", submitJob)
+	r.GET("/jobs/:id", getJob)
+	r.DELETE("/jobs/:id", cancelJob)
+	r.GET("/jobs/:id/events", jobEvents)
+	r.GET("/printers", listPrinters)
+	r.POST("/printers/:addr/select", selectPrinter)
+	r.GET("/info", getInfo)
 
 	// Start server
 	fmt.Println("Cat Printer Server starting on port 8080...")
@@ -62,17 +102,26 @@ func main() {
 	fmt.Println("  GET  /status       - Get printer status")
 
 	// connect to printer after starting webserver
-	p.Connect()
+	p.Connect(PrinterTarget{})
 
 	log.Fatal(r.Run(":8080"))
 }
 
 func getStatus(c *gin.Context) {
 	p.UpdateStatus()
+
+	errNames := make([]string, len(p.lastStatus.Errors))
+	for i, e := range p.lastStatus.Errors {
+		errNames[i] = e.String()
+	}
+
 	status := StatusResponse{
 		Connected: p.lastStatus.Connected,
 		Battery:   p.lastStatus.Battery,
-		Status:    p.lastStatus.StatusString,
+		State:     p.lastStatus.State.String(),
+		Errors:    errNames,
+		MTU:       p.lastStatus.MTU,
+		Message:   p.lastStatus.HumanReadable(),
 	}
 	c.JSON(http.StatusOK, status)
 }
@@ -84,3 +133,204 @@ func testPrint(c *gin.Context) {
 		"message": "Test print successful",
 	})
 }
+
+// print renders the request and hands the bitmap straight to the printer,
+// blocking until the transfer finishes. Kept for simple one-shot callers;
+// submitJob below is the non-blocking, cancellable equivalent.
+func print(c *gin.Context) {
+	bitmap, lineCount, err := renderPrintRequest(c)
+	if err != nil {
+		return // renderPrintRequest already wrote the error response
+	}
+
+	if err := p.Print(bitmap, lineCount); err != nil {
+		c.JSON(http.StatusInternalServerError, PrintResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, PrintResponse{Success: true, Message: "print job sent"})
+}
+
+// renderPrintRequest binds a PrintRequest from the body and renders it to
+// a bitmap, writing a JSON error response itself on failure.
+func renderPrintRequest(c *gin.Context) ([]byte, int, error) {
+	var req PrintRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, PrintResponse{Success: false, Message: err.Error()})
+		return nil, 0, err
+	}
+
+	job := render.Job{
+		Text:        req.Text,
+		BarcodeData: req.QRCode,
+		FontSize:    req.FontSize,
+	}
+	if req.ImageBase64 != "" {
+		imageData, err := base64.StdEncoding.DecodeString(req.ImageBase64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, PrintResponse{Success: false, Message: "invalid image_base64: " + err.Error()})
+			return nil, 0, err
+		}
+		job.Image = imageData
+	}
+
+	bitmap, lineCount, err := render.Render(job)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, PrintResponse{Success: false, Message: err.Error()})
+		return nil, 0, err
+	}
+	return bitmap, lineCount, nil
+}
+
+// submitJob renders the request and queues it, returning immediately with
+// a job ID the caller can poll or cancel instead of blocking on the
+// transfer.
+func submitJob(c *gin.Context) {
+	bitmap, lineCount, err := renderPrintRequest(c)
+	if err != nil {
+		return
+	}
+
+	id, err := jobQueue.SubmitJob(bitmap, lineCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, PrintResponse{Success: false, Message: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"id": id})
+}
+
+func getJob(c *gin.Context) {
+	job, ok := jobQueue.Job(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, jobToResponse(job))
+}
+
+func cancelJob(c *gin.Context) {
+	if err := jobQueue.CancelJob(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// jobEvents streams progress updates for a job as Server-Sent Events until
+// the job reaches a terminal state or the client disconnects.
+func jobEvents(c *gin.Context) {
+	job, ok := jobQueue.Job(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	}
+
+	updates, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	c.SSEvent("progress", jobToResponse(job))
+	c.Writer.Flush()
+
+	for {
+		switch job.State() {
+		case JobDone, JobCancelled, JobFailed:
+			c.SSEvent("progress", jobToResponse(job))
+			c.Writer.Flush()
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-updates:
+			c.SSEvent("progress", jobToResponse(job))
+			c.Writer.Flush()
+		case <-time.After(2 * time.Second):
+			// Fallback poll in case the job finished between our last
+			// state check and subscribing to its next update.
+		}
+	}
+}
+
+// PrinterResponse describes one nearby printer found by Discover.
+type PrinterResponse struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	RSSI    int16  `json:"rssi"`
+}
+
+// listPrinters scans for a few seconds and returns every cat printer in
+// range, so a multi-printer host can let the caller pick which one to use.
+func listPrinters(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	discovered, err := p.Discover(ctx, 5*time.Second)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	printers := make([]PrinterResponse, len(discovered))
+	for i, d := range discovered {
+		printers[i] = PrinterResponse{Name: d.Name, Address: d.Address.String(), RSSI: d.RSSI}
+	}
+	c.JSON(http.StatusOK, gin.H{"printers": printers})
+}
+
+// selectPrinter disconnects from whatever printer is currently active (if
+// any) and connects to the one at :addr instead.
+func selectPrinter(c *gin.Context) {
+	addr, err := bluetooth.ParseMAC(c.Param("addr"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid address: " + err.Error()})
+		return
+	}
+
+	if p.IsConnected() {
+		if err := p.Disconnect(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	target := ByAddress(bluetooth.Address{MACAddress: bluetooth.MACAddress{MAC: addr}})
+	if err := p.Connect(target); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "selected": target.String()})
+}
+
+// InfoResponse is the JSON shape for GET /info: everything useful for
+// troubleshooting or for feature-gating protocol quirks by firmware
+// version.
+type InfoResponse struct {
+	Model            string `json:"model"`
+	FirmwareVersion  string `json:"firmware_version"`
+	HardwareRevision string `json:"hardware_revision"`
+	SerialNumber     string `json:"serial_number"`
+	Battery          int    `json:"battery"`
+}
+
+func getInfo(c *gin.Context) {
+	info, err := p.GetVersion()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, InfoResponse{
+		Model:            p.Target().String(),
+		FirmwareVersion:  info.FirmwareVersion,
+		HardwareRevision: info.HardwareRevision,
+		SerialNumber:     info.SerialNumber,
+		Battery:          p.lastStatus.Battery,
+	})
+}
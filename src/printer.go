@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/Codaea/cattaskprinter/src/render"
 	"tinygo.org/x/bluetooth"
 )
 
@@ -33,27 +35,98 @@ const (
 	ImageWidth      = 384  // pixels
 	ImageWidthBytes = 48   // 384/8
 	MinImageBytes   = 4320 // minimum padding
+
+	// MTU negotiation
+	maxATTMTU      = 517 // BLE spec maximum
+	attHeaderBytes = 3   // opcode + handle, subtracted to get the usable payload
+	minChunkSize   = 20  // fallback if negotiation fails or reports something tiny
+
+	// Adaptive backpressure for transferImageData
+	writeBackoffInitial = 5 * time.Millisecond
+	writeBackoffMax     = 200 * time.Millisecond
+	writeMaxRetries     = 5
 )
 
 type CatPrinter struct {
 	adapter       *bluetooth.Adapter
-	device        bluetooth.Device
 	controlChar   bluetooth.DeviceCharacteristic
 	notifyChar    bluetooth.DeviceCharacteristic
 	dataChar      bluetooth.DeviceCharacteristic
-	connected     bool
 	notifications chan []byte
 	lastStatus    *PrinterStatus
+
+	// chunkSize is the effective payload size for WriteWithoutResponse,
+	// derived from the negotiated ATT MTU. Defaults to minChunkSize until
+	// negotiateMTU runs.
+	chunkSize int
+
+	// bleMu serializes every control-characteristic request/response
+	// round trip (status, print, cancel, ...), since they all share the
+	// single notifications channel. Without it, a /status call racing a
+	// print's transferImageData could steal the notification the print
+	// was waiting on.
+	bleMu sync.Mutex
+
+	// connMu guards connected, device, target and disconnecting, which
+	// the reconnect supervisor goroutine (and its adapter callback, run
+	// on whatever goroutine the BLE stack chooses) reads/writes
+	// independently of whatever called Connect or Disconnect.
+	connMu        sync.RWMutex
+	connected     bool
+	device        bluetooth.Device
+	target        PrinterTarget // last resolved target, used to reconnect
+	disconnecting bool          // set while Disconnect() is tearing down a deliberate disconnect
+
+	// connectSeq serializes the whole Connect sequence (scan, connect,
+	// service/characteristic discovery, enabling notifications), so two
+	// concurrent callers - e.g. selectPrinter racing the reconnect
+	// supervisor - can't interleave writes to device/controlChar/
+	// notifyChar/dataChar. bleMu alone isn't enough since it only wraps
+	// individual command round trips, not the connect sequence itself.
+	connectSeq sync.Mutex
+
+	// deviceInfo is cached on first GetVersion call, since firmware/
+	// hardware identity doesn't change for the life of a connection.
+	deviceInfo *DeviceInfo
 }
 
 type PrinterStatus struct {
-	Connected    bool
-	Battery      int
-	Temperature  int
-	Status       int    // 0=Standby, 1=Printing
-	ErrorFlag    int    // 0=OK, anything else is an error
-	ErrorCode    int    // Error details
-	StatusString string // Human-readable status
+	Connected   bool
+	Battery     int
+	Temperature int
+	State       PrinterState
+	Errors      []PrinterError
+	MTU         int // negotiated ATT MTU, for diagnostics
+}
+
+// IsConnected reports whether the printer currently has a live BLE
+// connection. Safe to call from any goroutine, including the reconnect
+// supervisor.
+func (p *CatPrinter) IsConnected() bool {
+	p.connMu.RLock()
+	defer p.connMu.RUnlock()
+	return p.connected
+}
+
+func (p *CatPrinter) setConnected(connected bool) {
+	p.connMu.Lock()
+	p.connected = connected
+	p.connMu.Unlock()
+}
+
+// getDevice returns the currently connected device. Safe to call from any
+// goroutine, including the adapter's connect-handler callback, which the
+// BLE stack may invoke on a goroutine of its own choosing.
+func (p *CatPrinter) getDevice() bluetooth.Device {
+	p.connMu.RLock()
+	defer p.connMu.RUnlock()
+	return p.device
+}
+
+func (p *CatPrinter) setDevice(device bluetooth.Device) {
+	p.connMu.Lock()
+	p.device = device
+	p.connMu.Unlock()
 }
 
 func ifErrNotNil(err error, message string) {
@@ -70,31 +143,50 @@ func NewCatPrinter() (*CatPrinter, error) {
 	printer := &CatPrinter{
 		adapter:       adapter,
 		notifications: make(chan []byte, 10),
+		chunkSize:     minChunkSize,
 		lastStatus: &PrinterStatus{
-			StatusString: "Not connected",
+			State: StateUnknown,
 		},
 	}
 	return printer, nil
 }
 
-func (p *CatPrinter) Connect() error {
-	if p.connected {
+// Connect scans for and connects to a printer matching target. The zero
+// value PrinterTarget{} (or ByName("")/ByAddress of the zero address)
+// matches any device on DefaultAllowList - pass ByName or ByAddress to pin
+// a specific printer when more than one is in range.
+func (p *CatPrinter) Connect(target PrinterTarget) error {
+	p.connectSeq.Lock()
+	defer p.connectSeq.Unlock()
+
+	if p.IsConnected() {
 		return nil
 	}
 
-	fmt.Println("Scanning for cat printer...")
+	// Clear any disconnecting flag left by a prior Disconnect() now that
+	// we're committing to a new connection attempt. We deliberately don't
+	// clear it right after device.Disconnect() returns in Disconnect()
+	// itself: on backends that deliver the disconnect callback
+	// asynchronously (e.g. CoreBluetooth on Darwin), that callback can
+	// still be in flight for the old device when Disconnect() returns,
+	// and clearing the flag too early would let supervise() mistake it
+	// for an unexpected drop.
+	p.connMu.Lock()
+	p.disconnecting = false
+	p.connMu.Unlock()
+
+	fmt.Printf("Scanning for cat printer (target: %s)...\n", target.String())
 	var printerAddr bluetooth.Address
+	var printerName string
 	found := false
 
-	// CONNECT TO OUR FUCKING PRINTER
-	knownName := "MXW01"
-
 	err := p.adapter.Scan(func(adapter *bluetooth.Adapter, device bluetooth.ScanResult) {
 		name := device.LocalName()
 		fmt.Printf("Found device: %s (%s)\n", name, device.Address.String())
 
-		if name == knownName {
+		if target.matches(name, device.Address, DefaultAllowList) {
 			printerAddr = device.Address
+			printerName = name
 			found = true
 			adapter.StopScan()
 			return
@@ -120,7 +212,7 @@ func (p *CatPrinter) Connect() error {
 	device, err := p.adapter.Connect(printerAddr, bluetooth.ConnectionParams{})
 	ifErrNotNil(err, "failed to connect to printer")
 
-	p.device = *device
+	p.setDevice(device)
 
 	// Discover services
 	services, err := device.DiscoverServices(nil)
@@ -176,24 +268,83 @@ func (p *CatPrinter) Connect() error {
 
 	fmt.Println("DEBUG: Notifications enabled successfully")
 
+	p.negotiateMTU()
+
+	// Record the device we actually matched, not the caller's (possibly
+	// wildcard) criteria - Target()/diagnostics endpoints and the
+	// reconnect supervisor all want the resolved identity, e.g. "MXW01"
+	// instead of "any".
+	resolved := ByName(printerName)
+	if printerName == "" {
+		resolved = ByAddress(printerAddr)
+	}
+
+	p.connMu.Lock()
 	p.connected = true
+	p.target = resolved
+	p.connMu.Unlock()
+
 	fmt.Println("Successfully connected to cat printer!")
 
+	p.supervise()
+
 	// Update status
 	p.UpdateStatus()
 
+	// Cache firmware/hardware identity up front, similar to how IEEE-1284
+	// Device ID strings get cached on connect for other label printer
+	// drivers - it's cheap, rarely changes, and feature-gating protocol
+	// quirks by firmware version needs it available early.
+	if _, err := p.GetVersion(); err != nil {
+		fmt.Printf("DEBUG: failed to query device version: %v\n", err)
+	}
+
 	return nil
 }
 
+// negotiateMTU reads back the ATT MTU the link actually negotiated during
+// connection setup and records the effective per-write payload size, so
+// transferImageData can send large writes instead of the minimum 20-byte
+// chunks. The bluetooth package has no API to request a specific MTU - the
+// stack negotiates it automatically on connect - so this only queries the
+// result via dataChar.GetMTU(). Falls back to minChunkSize if the query
+// isn't supported on this OS/backend or reports an MTU smaller than that.
+func (p *CatPrinter) negotiateMTU() {
+	negotiated, err := p.dataChar.GetMTU()
+	if err != nil {
+		fmt.Printf("DEBUG: MTU query failed, falling back to %d-byte chunks: %v\n", minChunkSize, err)
+		p.chunkSize = minChunkSize
+		return
+	}
+
+	effective := int(negotiated) - attHeaderBytes
+	if effective < minChunkSize {
+		effective = minChunkSize
+	}
+	p.chunkSize = effective
+	p.lastStatus.MTU = int(negotiated)
+	fmt.Printf("Negotiated ATT MTU: %d (chunk size %d bytes)\n", negotiated, p.chunkSize)
+}
+
 func (p *CatPrinter) Disconnect() error {
-	if !p.connected {
+	if !p.IsConnected() {
 		return nil
 	}
 
-	err := p.device.Disconnect()
-	p.connected = false
+	// Mark this as a deliberate disconnect so supervise()'s connect
+	// handler doesn't mistake it for an unexpected drop and spawn a
+	// reconnect for the device we're intentionally leaving. Left set
+	// until the next Connect() call, since some backends deliver the
+	// disconnect callback asynchronously, after this function returns.
+	p.connMu.Lock()
+	p.disconnecting = true
+	p.connMu.Unlock()
+
+	err := p.getDevice().Disconnect()
+	p.setConnected(false)
 	p.lastStatus.Connected = false
-	p.lastStatus.StatusString = "Disconnected"
+	p.lastStatus.State = StateUnknown
+	p.deviceInfo = nil
 
 	return err
 }
@@ -220,8 +371,20 @@ func calculateCRC8(data []byte) byte {
 	return crc
 }
 
+// UpdateStatus sends a Get Status (A1) command and blocks for the reply,
+// acquiring bleMu so it can't race an in-flight print for the next
+// notification.
 func (p *CatPrinter) UpdateStatus() error {
-	if !p.connected {
+	p.bleMu.Lock()
+	defer p.bleMu.Unlock()
+	return p.updateStatusLocked()
+}
+
+// updateStatusLocked is UpdateStatus without acquiring bleMu, for callers
+// (like printWithProgress) that already hold it for the duration of a
+// larger multi-command exchange.
+func (p *CatPrinter) updateStatusLocked() error {
+	if !p.IsConnected() {
 		return fmt.Errorf("printer not connected")
 	}
 
@@ -304,54 +467,59 @@ func (p *CatPrinter) parseStatusResponse(buf []byte) error {
 			p.lastStatus.Temperature = int(buf[10])
 		}
 		if len(buf) > 6 {
-			p.lastStatus.Status = int(buf[6]) // 0x00 = Standby, 0x01 = Printing, etc
+			p.lastStatus.State = printerStateFromByte(buf[6])
 		}
 
-		// Check error flag
+		// Check error flag - bits 0..7 each name a distinct fault condition
+		p.lastStatus.Errors = nil
 		if len(buf) > 12 {
-			p.lastStatus.ErrorFlag = int(buf[12]) // 0x00 = OK, anything else is an error
-			if p.lastStatus.ErrorFlag != 0 && len(buf) > 13 {
-				p.lastStatus.ErrorCode = int(buf[13]) // Error details
+			p.lastStatus.Errors = decodeErrorFlags(buf[12])
+			if len(buf) > 13 {
+				if named, ok := errorCodeTable[int(buf[13])]; ok {
+					p.lastStatus.Errors = append(p.lastStatus.Errors, named)
+				}
 			}
 		}
 
-		p.lastStatus.StatusString = fmt.Sprintf("Battery: %d%%, Temp: %d°C, Status: %d, Error: %d",
-			p.lastStatus.Battery, p.lastStatus.Temperature, p.lastStatus.Status, p.lastStatus.ErrorFlag)
-
 	} else {
 		return fmt.Errorf("status payload too short: %d bytes", len(buf))
 	}
 
-	fmt.Printf("Printer Status: Connected=%v, Battery=%d%%, Temp=%d°C, Status=%s\n",
-		p.lastStatus.Connected, p.lastStatus.Battery, p.lastStatus.Temperature, p.lastStatus.StatusString)
+	fmt.Printf("Printer Status: Connected=%v, %s\n", p.lastStatus.Connected, p.lastStatus.HumanReadable())
 
 	return nil
 }
 
+// TestPrintCard renders a small test pattern through the render subsystem
+// and sends it to the printer, exercising the same path as a real /print
+// request.
 func (p *CatPrinter) TestPrintCard() error {
-	// Create proper test image data for 10 lines of 384 pixels each
-	// Each line needs 48 bytes (384 pixels / 8 bits per byte)
-	lineBytes := ImageWidthBytes // 48 bytes per line
-	lineCount := 10
-	totalBytes := lineBytes * lineCount // 480 bytes
-
-	imageData := make([]byte, totalBytes)
-
-	// Create a simple test pattern: alternating black and white blocks
-	for line := 0; line < lineCount; line++ {
-		for byteIdx := 0; byteIdx < lineBytes; byteIdx++ {
-			// Create alternating pattern every 8 pixels (1 byte)
-			if (byteIdx % 2) == (line % 2) {
-				imageData[line*lineBytes+byteIdx] = 0xFF // Black pixels
-			} else {
-				imageData[line*lineBytes+byteIdx] = 0x00 // White pixels
-			}
-		}
+	bitmap, lineCount, err := render.Render(render.Job{Text: "Cat Printer Test Card"})
+	if err != nil {
+		return fmt.Errorf("failed to render test card: %v", err)
 	}
 
-	fmt.Printf("Created test image: %d lines, %d bytes total\n", lineCount, len(imageData))
+	fmt.Printf("Rendered test card: %d lines, %d bytes total\n", lineCount, len(bitmap))
 
-	p.UpdateStatus()
+	return p.Print(bitmap, lineCount)
+}
+
+// Print sends a pre-rendered 1-bpp bitmap (as produced by the render
+// package) to the printer. bitmap must be lineCount*ImageWidthBytes bytes.
+// Callers that want progress updates or the ability to cancel mid-transfer
+// should go through JobQueue.SubmitJob instead.
+func (p *CatPrinter) Print(bitmap []byte, lineCount int) error {
+	return p.printWithProgress(bitmap, lineCount, nil, nil)
+}
+
+// printWithProgress is Print plus optional progress reporting and
+// cancellation, used directly by JobQueue so a submitted job can be
+// cancelled mid-transfer.
+func (p *CatPrinter) printWithProgress(bitmap []byte, lineCount int, progress func(JobProgress), cancel <-chan struct{}) error {
+	p.bleMu.Lock()
+	defer p.bleMu.Unlock()
+
+	p.updateStatusLocked()
 	p.setIntensity(93) // Set intensity to 93 (0x5D)
 
 	fmt.Println("DEBUG: Starting print request...")
@@ -364,12 +532,12 @@ func (p *CatPrinter) TestPrintCard() error {
 	}
 
 	// Check for errors
-	if p.lastStatus.ErrorFlag != 0 {
-		return fmt.Errorf("printer error: %d", p.lastStatus.ErrorFlag)
+	if len(p.lastStatus.Errors) > 0 {
+		return fmt.Errorf("printer error: %v", p.lastStatus.Errors)
 	}
 
 	// Check if not currently printing
-	if p.lastStatus.Status != 0 {
+	if p.lastStatus.State != StateStandby {
 		return fmt.Errorf("printer is currently printing, cannot start new print")
 	}
 
@@ -425,7 +593,7 @@ func (p *CatPrinter) TestPrintCard() error {
 				payloadStart := 6 // After preamble, cmd, unknown, length
 				if buf[payloadStart] == 0x00 {
 					fmt.Println("Print request accepted!")
-					return p.transferImageData(imageData)
+					return p.transferImageData(bitmap, progress, cancel)
 				} else {
 					return fmt.Errorf("print request rejected, response code: 0x%02X", buf[payloadStart])
 				}
@@ -440,7 +608,17 @@ func (p *CatPrinter) TestPrintCard() error {
 	}
 }
 
-func (p *CatPrinter) transferImageData(imageData []byte) error {
+// errJobCancelled is returned by transferImageData when the cancel channel
+// fires mid-transfer, so callers (JobQueue) can tell a cancellation apart
+// from a transport error.
+var errJobCancelled = fmt.Errorf("print job cancelled")
+
+// transferImageData streams imageData to the printer's data characteristic
+// in small chunks. If progress is non-nil, it's called with a JobProgress
+// after every chunk. If cancel is non-nil and fires, the transfer aborts
+// immediately and returns errJobCancelled instead of proceeding to the
+// flush command.
+func (p *CatPrinter) transferImageData(imageData []byte, progress func(JobProgress), cancel <-chan struct{}) error {
 	fmt.Println("DEBUG: Starting image data transfer...")
 
 	// Ensure minimum padding (4320 bytes minimum according to protocol) (about 90 lines)
@@ -451,27 +629,44 @@ func (p *CatPrinter) transferImageData(imageData []byte) error {
 		fmt.Printf("Padded image data to %d bytes\n", len(paddedData))
 	}
 
-	// send data in chunks of to avoid buffer overflow
-	chunkSize := 20 // 20 bytes per chunk
+	// Chunk at the negotiated MTU-derived size instead of the old hardcoded
+	// 20 bytes, so a typical ~185-244 byte MXW01 negotiation moves in far
+	// fewer writes.
+	chunkSize := p.chunkSize
+	if chunkSize <= 0 {
+		chunkSize = minChunkSize
+	}
 
 	for i := 0; i < len(paddedData); i += chunkSize {
+		if cancel != nil {
+			select {
+			case <-cancel:
+				fmt.Println("DEBUG: Transfer cancelled, aborting chunk loop")
+				return errJobCancelled
+			default:
+			}
+		}
+
 		end := i + chunkSize
 		if end > len(paddedData) {
 			end = len(paddedData)
 		}
 		chunk := paddedData[i:end]
 
-		fmt.Printf("Sending chunk %d: %x\n", i/chunkSize, chunk)
-		_, err := p.dataChar.WriteWithoutResponse(chunk)
-		ifErrNotNil(err, fmt.Sprintf("failed to send data chunk %d", i/chunkSize))
-
-		time.Sleep(10 * time.Millisecond) // small delay to avoid flooding the buffer
+		fmt.Printf("Sending chunk %d: %d bytes\n", i/chunkSize, len(chunk))
+		if err := p.writeChunkWithBackoff(chunk); err != nil {
+			return fmt.Errorf("failed to send data chunk %d: %v", i/chunkSize, err)
+		}
 
 		if (i/chunkSize)%10 == 0 { // Progress update every 10 chunks
 			fmt.Printf("Sent %d/%d bytes (%.1f%%)\n",
 				end, len(paddedData),
 				float64(end)/float64(len(paddedData))*100)
 		}
+
+		if progress != nil {
+			progress(JobProgress{State: JobPrinting, BytesSent: end, TotalBytes: len(paddedData)})
+		}
 	}
 	fmt.Println("Image data transfer complete, sending flush command...")
 	err := p.flushData()
@@ -481,8 +676,77 @@ func (p *CatPrinter) transferImageData(imageData []byte) error {
 
 }
 
+// writeChunkWithBackoff writes one chunk to the data characteristic. On
+// success it returns immediately with no delay, since the BLE stack itself
+// provides flow control; it only sleeps when a write fails, on the
+// assumption that's the controller's buffer being full, and retries with
+// exponential backoff before giving up.
+func (p *CatPrinter) writeChunkWithBackoff(chunk []byte) error {
+	delay := writeBackoffInitial
+	var lastErr error
+
+	for attempt := 0; attempt <= writeMaxRetries; attempt++ {
+		if _, err := p.dataChar.WriteWithoutResponse(chunk); err == nil {
+			return nil
+		} else {
+			lastErr = err
+		}
+
+		fmt.Printf("DEBUG: write failed (attempt %d/%d), backing off %v: %v\n",
+			attempt+1, writeMaxRetries+1, delay, lastErr)
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > writeBackoffMax {
+			delay = writeBackoffMax
+		}
+	}
+
+	return lastErr
+}
+
+// sendCancelPrint sends the 0xAC control packet that aborts an in-progress
+// print, then drains any notification it produces so it doesn't get
+// mistaken for the response to a later command. Takes bleMu like every
+// other control round trip, so it can't steal the notification
+// printWithProgress/flushData is waiting on for the job being cancelled -
+// CancelJob already closes that job's cancel channel first, so the
+// transfer loop exits and releases bleMu within one chunk.
+func (p *CatPrinter) sendCancelPrint() error {
+	p.bleMu.Lock()
+	defer p.bleMu.Unlock()
+
+	payload := []byte{0x00}
+	crc := calculateCRC8(payload)
+
+	packet := []byte{
+		Preamble1,      // 0x22
+		Preamble2,      // 0x21
+		CmdCancelPrint, // 0xAC
+		0x00,           // Fixed byte
+		0x01, 0x00,     // Length (1 byte payload, little endian)
+		0x00,   // Payload
+		crc,    // CRC8 checksum
+		Footer, // 0xFF
+	}
+
+	_, err := p.controlChar.WriteWithoutResponse(packet)
+	if err != nil {
+		return fmt.Errorf("failed to send cancel-print command: %v", err)
+	}
+
+	// Drain whatever the printer acks the cancel with, if anything.
+	select {
+	case buf := <-p.notifications:
+		fmt.Printf("DEBUG: Cancel-print response: %x\n", buf)
+	case <-time.After(1 * time.Second):
+	}
+
+	return nil
+}
+
 func (p *CatPrinter) flushData() error {
-	if !p.connected {
+	if !p.IsConnected() {
 		return fmt.Errorf("printer not connected")
 	}
 
@@ -525,7 +789,7 @@ func (p *CatPrinter) flushData() error {
 }
 
 func (p *CatPrinter) setIntensity(intensity int) error {
-	if !p.connected {
+	if !p.IsConnected() {
 		return fmt.Errorf("printer not connected")
 	}
 
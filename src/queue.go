@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JobState tracks where a PrintJob is in its lifecycle.
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobPrinting  JobState = "printing"
+	JobDone      JobState = "done"
+	JobCancelled JobState = "cancelled"
+	JobFailed    JobState = "failed"
+)
+
+// JobProgress is a point-in-time snapshot of a job's transfer progress,
+// published as transferImageData streams chunks.
+type JobProgress struct {
+	JobID      string   `json:"job_id"`
+	State      JobState `json:"state"`
+	BytesSent  int      `json:"bytes_sent"`
+	TotalBytes int      `json:"total_bytes"`
+}
+
+// PrintJob is one submission to the JobQueue: a rendered bitmap waiting
+// for (or currently getting) its turn on the single BLE connection.
+type PrintJob struct {
+	ID        string
+	Bitmap    []byte
+	LineCount int
+
+	mu          sync.Mutex
+	state       JobState
+	err         error
+	bytesSent   int
+	totalBytes  int
+	subscribers []chan JobProgress
+
+	cancel chan struct{}
+	once   sync.Once
+}
+
+func (j *PrintJob) setState(s JobState) {
+	j.mu.Lock()
+	j.state = s
+	j.mu.Unlock()
+}
+
+func (j *PrintJob) State() JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state
+}
+
+func (j *PrintJob) setErr(err error) {
+	j.mu.Lock()
+	j.err = err
+	j.mu.Unlock()
+}
+
+func (j *PrintJob) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Progress returns the most recently reported bytes-sent/total-bytes pair.
+func (j *PrintJob) Progress() (bytesSent, totalBytes int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.bytesSent, j.totalBytes
+}
+
+// publish records a progress update and fans it out (non-blocking) to any
+// subscribers, e.g. the /jobs/:id/events SSE stream.
+func (j *PrintJob) publish(p JobProgress) {
+	j.mu.Lock()
+	j.bytesSent = p.BytesSent
+	j.totalBytes = p.TotalBytes
+	subs := append([]chan JobProgress(nil), j.subscribers...)
+	j.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- p:
+		default: // subscriber isn't keeping up, drop this update
+		}
+	}
+}
+
+// Subscribe registers a channel that receives every future progress update
+// for this job. Call the returned func to unsubscribe and release it.
+func (j *PrintJob) Subscribe() (<-chan JobProgress, func()) {
+	ch := make(chan JobProgress, 16)
+
+	j.mu.Lock()
+	j.subscribers = append(j.subscribers, ch)
+	j.mu.Unlock()
+
+	unsubscribe := func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		for i, sub := range j.subscribers {
+			if sub == ch {
+				j.subscribers = append(j.subscribers[:i], j.subscribers[i+1:]...)
+				break
+			}
+		}
+	}
+	return ch, unsubscribe
+}
+
+// requestCancel closes the job's cancel channel exactly once, so
+// transferImageData (which selects on it) unblocks regardless of how many
+// times CancelJob is called.
+func (j *PrintJob) requestCancel() {
+	j.once.Do(func() { close(j.cancel) })
+}
+
+// JobQueue serializes PrintJob submissions onto the printer's single BLE
+// connection via one worker goroutine, so an HTTP client can submit jobs
+// (and poll /status) without racing transferImageData on the notifications
+// channel.
+type JobQueue struct {
+	printer *CatPrinter
+
+	mu   sync.Mutex
+	jobs map[string]*PrintJob
+
+	submit chan *PrintJob
+}
+
+// NewJobQueue starts the worker goroutine that drains submitted jobs one
+// at a time and sends them to printer.
+func NewJobQueue(printer *CatPrinter) *JobQueue {
+	q := &JobQueue{
+		printer: printer,
+		jobs:    make(map[string]*PrintJob),
+		submit:  make(chan *PrintJob, 16),
+	}
+	go q.run()
+	return q
+}
+
+func (q *JobQueue) run() {
+	for job := range q.submit {
+		q.runJob(job)
+	}
+}
+
+func (q *JobQueue) runJob(job *PrintJob) {
+	// If the printer dropped mid-session, pause here rather than failing
+	// the job - the reconnect supervisor will bring the link back up, and
+	// we resend the whole job once it does.
+	for !q.printer.IsConnected() {
+		select {
+		case <-job.cancel:
+			job.setState(JobCancelled)
+			return
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	job.setState(JobPrinting)
+
+	err := q.printer.printWithProgress(job.Bitmap, job.LineCount, job.publish, job.cancel)
+
+	switch {
+	case err == errJobCancelled:
+		job.setState(JobCancelled)
+	case err != nil:
+		job.setErr(err)
+		job.setState(JobFailed)
+	default:
+		job.setState(JobDone)
+	}
+}
+
+// SubmitJob queues a rendered bitmap for printing and returns its job ID
+// immediately; the job prints asynchronously on the queue's worker.
+func (q *JobQueue) SubmitJob(bitmap []byte, lineCount int) (string, error) {
+	if len(bitmap) == 0 {
+		return "", fmt.Errorf("empty bitmap")
+	}
+
+	job := &PrintJob{
+		ID:         newJobID(),
+		Bitmap:     bitmap,
+		LineCount:  lineCount,
+		state:      JobQueued,
+		totalBytes: len(bitmap),
+		cancel:     make(chan struct{}),
+	}
+
+	q.mu.Lock()
+	q.jobs[job.ID] = job
+	q.mu.Unlock()
+
+	q.submit <- job
+	return job.ID, nil
+}
+
+// Job looks up a previously submitted job by ID.
+func (q *JobQueue) Job(id string) (*PrintJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	return job, ok
+}
+
+// CancelJob sends the 0xAC cancel-print control packet and aborts the
+// in-flight transfer loop for id, if it's the job currently printing.
+// Queued-but-not-started jobs are simply marked cancelled and never sent.
+// Jobs already in a terminal state are left alone - forwarding the cancel
+// to the printer would abort whatever other job is printing right now,
+// since the BLE link is shared across the whole queue.
+func (q *JobQueue) CancelJob(id string) error {
+	job, ok := q.Job(id)
+	if !ok {
+		return fmt.Errorf("job %s not found", id)
+	}
+
+	switch job.State() {
+	case JobQueued:
+		job.requestCancel()
+		job.setState(JobCancelled)
+		return nil
+	case JobDone, JobCancelled, JobFailed:
+		return fmt.Errorf("job %s already %s", id, job.State())
+	}
+
+	job.requestCancel()
+	return q.printer.sendCancelPrint()
+}
+
+// newJobID generates a short random hex ID for a job; good enough for an
+// in-memory queue on a single host.
+func newJobID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
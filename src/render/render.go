@@ -0,0 +1,222 @@
+// Package render turns print jobs (raw images, plain text, and barcodes/QR
+// codes) into 384-pixel-wide 1-bpp bitmaps ready for transferImageData.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // register JPEG decoder with image.Decode
+	_ "image/png"  // register PNG decoder with image.Decode
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// Width must match CatPrinter.ImageWidth - the printer only understands
+	// 384-pixel-wide lines.
+	Width      = 384
+	WidthBytes = Width / 8
+)
+
+// Job describes everything a caller might want printed. Image takes
+// priority over Text, and Text takes priority over Barcode - a caller
+// should only populate one of them, but we don't enforce that here.
+type Job struct {
+	Image       []byte // raw PNG or JPEG bytes
+	Text        string
+	BarcodeData string
+	BarcodeType string // "qr" or "code128", defaults to "qr"
+	FontSize    int    // unused for now, bitmap font is fixed size
+}
+
+// Render converts a Job into a 1-bpp bitmap packed MSB-first, 48 bytes per
+// row, along with the number of rows produced. The returned bitmap is ready
+// to hand to CatPrinter.Print.
+func Render(job Job) ([]byte, int, error) {
+	switch {
+	case len(job.Image) > 0:
+		img, err := decodeImage(job.Image)
+		if err != nil {
+			return nil, 0, fmt.Errorf("decode image: %v", err)
+		}
+		return ditherImage(img), imgRows(img), nil
+	case job.BarcodeData != "":
+		img, err := renderBarcode(job.BarcodeData, job.BarcodeType)
+		if err != nil {
+			return nil, 0, fmt.Errorf("render barcode: %v", err)
+		}
+		return ditherImage(img), imgRows(img), nil
+	case job.Text != "":
+		img := renderText(job.Text)
+		return ditherImage(img), imgRows(img), nil
+	default:
+		return nil, 0, fmt.Errorf("empty print job")
+	}
+}
+
+func imgRows(img image.Image) int {
+	return img.Bounds().Dy()
+}
+
+func decodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return resizeToWidth(img, Width), nil
+}
+
+// resizeToWidth scales img so its width is exactly Width, preserving aspect
+// ratio via nearest-neighbor sampling (good enough for 1-bpp output).
+func resizeToWidth(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW == width {
+		return img
+	}
+
+	dstH := srcH * width / srcW
+	dst := image.NewGray(image.Rect(0, 0, width, dstH))
+	for y := 0; y < dstH; y++ {
+		srcY := y * srcH / dstH
+		for x := 0; x < width; x++ {
+			srcX := x * srcW / width
+			dst.Set(x, y, img.At(b.Min.X+srcX, b.Min.Y+srcY))
+		}
+	}
+	return dst
+}
+
+// ditherImage converts img to grayscale (if it isn't already) and applies
+// Floyd-Steinberg error diffusion, packing the result MSB-first into
+// WidthBytes-byte rows.
+func ditherImage(img image.Image) []byte {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// Work on a float error buffer so diffusion doesn't clip until the end.
+	gray := make([][]int16, h)
+	for y := 0; y < h; y++ {
+		gray[y] = make([]int16, w)
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			lum := color.GrayModel.Convert(color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(bl), A: 0xffff}).(color.Gray).Y
+			gray[y][x] = int16(lum)
+		}
+	}
+
+	rowBytes := (w + 7) / 8
+	out := make([]byte, rowBytes*h)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			old := gray[y][x]
+			var newVal int16
+			if old < 128 {
+				// white pixel -> bit stays 0, black pixel -> bit set
+				newVal = 0
+				out[y*rowBytes+x/8] |= 0x80 >> uint(x%8)
+			} else {
+				newVal = 255
+			}
+			errVal := old - newVal
+			if errVal == 0 {
+				continue
+			}
+
+			if x+1 < w {
+				gray[y][x+1] += errVal * 7 / 16
+			}
+			if y+1 < h {
+				if x-1 >= 0 {
+					gray[y+1][x-1] += errVal * 3 / 16
+				}
+				gray[y+1][x] += errVal * 5 / 16
+				if x+1 < w {
+					gray[y+1][x+1] += errVal * 1 / 16
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// renderText rasterizes text as left-aligned lines wrapped at Width pixels
+// using a bundled bitmap face, so we don't depend on system fonts.
+func renderText(text string) image.Image {
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height.Ceil()
+	maxCharsPerLine := Width / face.Advance
+
+	lines := wrapText(text, maxCharsPerLine)
+	img := image.NewGray(image.Rect(0, 0, Width, lineHeight*len(lines)))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.Black,
+		Face: face,
+	}
+	for i, line := range lines {
+		d.Dot = fixed.P(0, (i+1)*lineHeight-face.Descent)
+		d.DrawString(line)
+	}
+	return img
+}
+
+// wrapText splits text into chunks of at most maxChars runes, so multi-byte
+// UTF-8 characters (accents, CJK, emoji) never get split across a line
+// boundary.
+func wrapText(text string, maxChars int) []string {
+	if maxChars <= 0 {
+		maxChars = 1
+	}
+	runes := []rune(text)
+	var lines []string
+	for len(runes) > maxChars {
+		lines = append(lines, string(runes[:maxChars]))
+		runes = runes[maxChars:]
+	}
+	lines = append(lines, string(runes))
+	return lines
+}
+
+// renderBarcode encodes data as either a QR code or a Code128 barcode,
+// scaled to the largest multiple of its module size that fits within Width.
+func renderBarcode(data, kind string) (image.Image, error) {
+	var code barcode.Barcode
+	var err error
+
+	switch kind {
+	case "", "qr":
+		code, err = qr.Encode(data, qr.M, qr.Auto)
+	case "code128":
+		code, err = code128.Encode(data)
+	default:
+		return nil, fmt.Errorf("unknown barcode type: %s", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := code.Bounds()
+	scale := Width / bounds.Dx()
+	if scale < 1 {
+		scale = 1
+	}
+	scaled, err := barcode.Scale(code, bounds.Dx()*scale, bounds.Dy()*scale)
+	if err != nil {
+		return nil, err
+	}
+	return resizeToWidth(scaled, Width), nil
+}
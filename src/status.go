@@ -0,0 +1,130 @@
+package main
+
+import "fmt"
+
+// PrinterState mirrors the single status byte (offset 6) returned by the
+// A1 Get Status response.
+type PrinterState int
+
+const (
+	StateStandby PrinterState = iota
+	StatePrinting
+	StateFeeding
+	StateUnknown
+)
+
+func (s PrinterState) String() string {
+	switch s {
+	case StateStandby:
+		return "standby"
+	case StatePrinting:
+		return "printing"
+	case StateFeeding:
+		return "feeding"
+	default:
+		return "unknown"
+	}
+}
+
+// printerStateFromByte maps the raw status byte to a PrinterState, falling
+// back to StateUnknown for anything the firmware hasn't told us about yet.
+func printerStateFromByte(b byte) PrinterState {
+	switch b {
+	case 0x00:
+		return StateStandby
+	case 0x01:
+		return StatePrinting
+	case 0x02:
+		return StateFeeding
+	default:
+		return StateUnknown
+	}
+}
+
+// PrinterError names a single fault condition. The zero value is never
+// produced by the decoder - only bits that are actually set turn into a
+// PrinterError.
+type PrinterError int
+
+const (
+	ErrNoPaper PrinterError = iota
+	ErrCoverOpen
+	ErrOverheat
+	ErrLowBattery
+	ErrHeadOverheat
+	ErrDataError
+	ErrUnknown
+)
+
+func (e PrinterError) String() string {
+	switch e {
+	case ErrNoPaper:
+		return "no paper"
+	case ErrCoverOpen:
+		return "cover open"
+	case ErrOverheat:
+		return "overheat"
+	case ErrLowBattery:
+		return "low battery"
+	case ErrHeadOverheat:
+		return "head overheat"
+	case ErrDataError:
+		return "data error"
+	default:
+		return "unknown error"
+	}
+}
+
+// errorFlagBits names each bit of the ErrorFlag byte, bit 0 first. An empty
+// string means the bit is reserved/unused by the firmware we've observed.
+var errorFlagBits = [8]PrinterError{
+	ErrNoPaper,
+	ErrCoverOpen,
+	ErrOverheat,
+	ErrLowBattery,
+	ErrHeadOverheat,
+	ErrDataError,
+	ErrUnknown,
+	ErrUnknown,
+}
+
+// errorCodeTable translates the single-byte ErrorCode field into a named
+// PrinterError, for firmware revisions that report one specific error
+// rather than (or in addition to) the ErrorFlag bitfield.
+var errorCodeTable = map[int]PrinterError{
+	0x01: ErrNoPaper,
+	0x02: ErrCoverOpen,
+	0x03: ErrOverheat,
+	0x04: ErrLowBattery,
+	0x05: ErrHeadOverheat,
+	0x06: ErrDataError,
+}
+
+// decodeErrorFlags walks bits 0..7 of the ErrorFlag byte, appending the
+// named PrinterError for each bit that's set.
+func decodeErrorFlags(flag byte) []PrinterError {
+	if flag == 0 {
+		return nil
+	}
+	var errs []PrinterError
+	for bit := 0; bit < 8; bit++ {
+		if flag&(1<<uint(bit)) != 0 {
+			errs = append(errs, errorFlagBits[bit])
+		}
+	}
+	return errs
+}
+
+// HumanReadable renders the status as a single line suitable for logs or
+// the JSON API, e.g. "Battery: 80%, Temp: 32C, State: printing, Errors: cover open".
+func (s *PrinterStatus) HumanReadable() string {
+	if len(s.Errors) == 0 {
+		return fmt.Sprintf("Battery: %d%%, Temp: %d°C, State: %s", s.Battery, s.Temperature, s.State)
+	}
+
+	errNames := make([]string, len(s.Errors))
+	for i, e := range s.Errors {
+		errNames[i] = e.String()
+	}
+	return fmt.Sprintf("Battery: %d%%, Temp: %d°C, State: %s, Errors: %v", s.Battery, s.Temperature, s.State, errNames)
+}